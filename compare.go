@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backend is a named way of computing ⌊π·10^d⌋, selectable with -compare.
+type backend struct {
+	name  string
+	floor func(d int) *big.Int
+}
+
+// backends lists the -compare candidates. A new algorithm joins the table here
+// and can then be run against the others at any size.
+var backends = []backend{
+	{"fft", func(d int) *big.Int { return piFloor(d, nil) }},
+	{"reference", piFloorRef},
+}
+
+// backendNamed returns the backend called name, if there is one.
+func backendNamed(name string) (backend, bool) {
+	for _, b := range backends {
+		if b.name == name {
+			return b, true
+		}
+	}
+	return backend{}, false
+}
+
+// parseCompare parses the -compare value "a,b" into two backends.
+func parseCompare(v string) (x, y backend, ok bool) {
+	a, b, found := strings.Cut(v, ",")
+	if !found {
+		return
+	}
+	x, okx := backendNamed(a)
+	y, oky := backendNamed(b)
+	return x, y, okx && oky
+}
+
+// backendNames returns the backend names in table order.
+func backendNames() []string {
+	names := make([]string, len(backends))
+	for i, b := range backends {
+		names[i] = b.name
+	}
+	return names
+}
+
+// piFloorRef is the stdlib-only reference pipeline: the serial binarySplit,
+// math/big's exact integer √, the exact quotient and 10^n by Exp — no FFT, no
+// Newton, no operand truncation. It is far slower than piFloor and exists to
+// be compared against.
+func piFloorRef(d int) *big.Int {
+	total := d + guardDigits
+	_, Q, R := binarySplit(1, terms(total))
+	ten := big.NewInt(10)
+	S := new(big.Int).Exp(ten, big.NewInt(int64(2*total)), nil)
+	S.Sqrt(S.Mul(S, big.NewInt(10005))) // ⌊√10005 · 10^total⌋
+	num := S.Mul(S, big.NewInt(426880))
+	num.Mul(num, Q)
+	den := new(big.Int).Add(new(big.Int).Mul(c13591409, Q), R)
+	v := num.Quo(num, den)
+	return v.Quo(v, new(big.Int).Exp(ten, big.NewInt(int64(guardDigits)), nil))
+}
+
+// firstDiff returns the index of the first byte at which a and b differ, the
+// shorter length if one is a prefix of the other, or -1 if they are equal.
+func firstDiff(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return n
+	}
+	return -1
+}
+
+// runCompare computes π to d places with backends x and y concurrently and
+// reports to w whether the expansions agree; on a mismatch it prints the first
+// divergent position, both contexts, and the precisions the run was sized to.
+func runCompare(w io.Writer, x, y backend, d int) bool {
+	var (
+		xs, ys     string
+		xdur, ydur time.Duration
+		wg         sync.WaitGroup
+	)
+	run := func(b backend, s *string, dur *time.Duration) {
+		defer wg.Done()
+		t := time.Now()
+		*s = b.floor(d).String()
+		*dur = time.Since(t)
+	}
+	wg.Add(2)
+	go run(x, &xs, &xdur)
+	go run(y, &ys, &ydur)
+	wg.Wait()
+
+	width := max(len(x.name), len(y.name))
+	fmt.Fprintf(w, "  %-*s %v\n", width, x.name, xdur)
+	fmt.Fprintf(w, "  %-*s %v\n", width, y.name, ydur)
+
+	i := firstDiff(xs, ys)
+	if i < 0 {
+		fmt.Fprintf(w, "All %d positions agree\n", len(xs))
+		return true
+	}
+	total := d + guardDigits
+	fmt.Fprintf(w, "DIVERGED at position %d (decimal place %d) of %d\n", i+1, i, d+1)
+	fmt.Fprintf(w, "  %-*s %s\n", width, x.name, around(xs, i))
+	fmt.Fprintf(w, "  %-*s %s\n", width, y.name, around(ys, i))
+	fmt.Fprintf(w, "  precision: %d places + %d guard, %d terms; fft truncates Q/R to %d bits\n",
+		d, guardDigits, terms(total), int(math.Ceil(float64(total)*log2of10))+64)
+	return false
+}
+
+// around returns s near index i as "...before[s[i]]after...", ctxWindow
+// characters either side, clipped to s.
+func around(s string, i int) string {
+	var b strings.Builder
+	b.WriteString("...")
+	b.WriteString(s[max(0, i-ctxWindow):min(i, len(s))])
+	if i < len(s) {
+		fmt.Fprintf(&b, "[%c]%s", s[i], s[i+1:min(len(s), i+1+ctxWindow)])
+	} else {
+		b.WriteString("[end]")
+	}
+	b.WriteString("...")
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestPiFloorRef(t *testing.T) {
+	for _, d := range []int{0, 1, 100, 1000} {
+		if got, want := piFloorRef(d).String(), "3"+piRef[2:2+d]; got != want {
+			t.Fatalf("piFloorRef(%d) = %s, want %s", d, got, want)
+		}
+	}
+}
+
+func TestFirstDiff(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", -1}, {"314", "314", -1}, {"314", "315", 2},
+		{"314", "31", 2}, {"31", "314", 2}, {"914", "314", 0},
+	}
+	for _, c := range cases {
+		if got := firstDiff(c.a, c.b); got != c.want {
+			t.Errorf("firstDiff(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+// TestRunCompare checks both outcomes: the shipped backends agree, and a
+// deliberately broken one is caught at the position it corrupts.
+func TestRunCompare(t *testing.T) {
+	fft, _ := backendNamed("fft")
+	ref, _ := backendNamed("reference")
+	var out strings.Builder
+	if !runCompare(&out, fft, ref, 20000) {
+		t.Fatalf("fft and reference disagree:\n%s", out.String())
+	}
+
+	broken := backend{"broken", func(d int) *big.Int {
+		v := piFloor(d, nil)
+		return v.Add(v, pow10(d-700)) // bump decimal place 700
+	}}
+	out.Reset()
+	if runCompare(&out, fft, broken, 1000) {
+		t.Fatal("runCompare missed a corrupted backend")
+	}
+	if !strings.Contains(out.String(), "DIVERGED at position 701 ") {
+		t.Fatalf("wrong divergence report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expansionFormat lays out an expansion ("3" followed by its places): the
+// places are split into groups of group digits and lines of line digits, and
+// each line ending on a multiple of marker places is tagged with that count.
+// A zero field disables that layer; the zero value is the bare "3.1415…".
+type expansionFormat struct{ group, line, marker int }
+
+// formatPresets are the layouts selectable with -format.
+var formatPresets = map[string]expansionFormat{
+	"plain":     {},
+	"classroom": {group: 5, line: 50, marker: 100},
+}
+
+// presetNames returns the -format preset names, sorted.
+func presetNames() []string {
+	names := make([]string, 0, len(formatPresets))
+	for n := range formatPresets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render formats s, an expansion as returned by big.Int.String or Text(16).
+// Continuation lines are indented past the "3." so the columns line up.
+func (f expansionFormat) render(s string) string {
+	if len(s) <= 1 {
+		return s
+	}
+	places := s[1:]
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/4)
+	b.WriteString(s[:1])
+	b.WriteByte('.')
+	for i := 0; i < len(places); i++ {
+		switch {
+		case i == 0:
+		case f.line > 0 && i%f.line == 0:
+			f.mark(&b, i)
+			b.WriteString("\n  ")
+		case f.group > 0 && i%f.group == 0:
+			b.WriteByte(' ')
+		}
+		b.WriteByte(places[i])
+	}
+	f.mark(&b, len(places))
+	return b.String()
+}
+
+// mark appends the position marker for a line ending after n places, if any.
+func (f expansionFormat) mark(b *strings.Builder, n int) {
+	if f.line > 0 && f.marker > 0 && n%f.marker == 0 {
+		fmt.Fprintf(b, "  [%d]", n)
+	}
+}
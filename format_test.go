@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPlain(t *testing.T) {
+	for _, s := range []string{"3", "31", "31415926535"} {
+		want := s
+		if len(s) > 1 {
+			want = s[:1] + "." + s[1:]
+		}
+		if got := formatPresets["plain"].render(s); got != want {
+			t.Errorf("plain render(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestRenderClassroom(t *testing.T) {
+	s := "3" + piRef[2:2+120]
+	lines := strings.Split(formatPresets["classroom"].render(s), "\n")
+	want := []string{
+		"3.14159 26535 89793 23846 26433 83279 50288 41971 69399 37510",
+		"  58209 74944 59230 78164 06286 20899 86280 34825 34211 70679  [100]",
+		"  82148 08651 32823 06647",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("classroom render: %d lines, want %d:\n%s", len(lines), len(want), strings.Join(lines, "\n"))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want[i])
+		}
+	}
+	// A marker on the final line too, when it ends on a multiple of 100.
+	if got := formatPresets["classroom"].render("3" + piRef[2:2+100]); !strings.HasSuffix(got, "70679  [100]") {
+		t.Errorf("classroom render of 100 places ends %q", got[len(got)-20:])
+	}
+}
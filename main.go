@@ -9,9 +9,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +31,8 @@ var (
 const (
 	// log2(10): bits required per decimal digit.
 	log2of10 = 3.321928094887362
+	// log16(10): hexadecimal places per decimal digit.
+	log16of10 = 0.8304820237218405
 	// Chudnovsky yields log10(640320³/1728) ≈ 14.1816 decimal digits per term.
 	digitsPerTerm = 14.181647462725477
 	// Decimal guard digits computed beyond what is requested. The pipeline's
@@ -35,7 +40,16 @@ const (
 	// (<2^-60), and the approximate division (±1 ulp) — total a few ulps, so
 	// the guard only needs to exceed the longest run of 9s/0s near the target
 	// (≤ 6 below 10⁶, well under 32 below ~5·10⁸).
-	guardDigits = 32
+	defaultGuardDigits = 32
+	// Smallest -guard-digits accepted: one digit for the few-ulp error plus
+	// the longest 9s/0s run below 10⁶.
+	minGuardDigits = 8
+	// Chudnovsky terms computed beyond digits/digitsPerTerm. At that count the
+	// first omitted term already sits below 10^-total (guardTermsUsed reports
+	// by how much), so these are margin; at least one keeps the split range
+	// [1, terms) non-empty for tiny totals.
+	defaultGuardTerms = 4
+	minGuardTerms     = 1
 	// Per-operand bit length at which bigfft.Mul beats stdlib Karatsuba on this
 	// class of inputs (measured crossover ≈ 160k–200k bits; below it bigfft
 	// switches to FFT too early and loses).
@@ -50,6 +64,22 @@ const (
 	serialCutoff = 2048
 )
 
+// Exit codes, so wrappers can tell failure classes apart. exitUsage matches
+// the flag package's own exit status for bad flags.
+const (
+	exitIO       = 1 // writing an output file failed
+	exitUsage    = 2 // invalid flags or flag combination
+	exitMismatch = 3 // -selftest, -quick or -compare found a wrong result
+)
+
+// The guard policy: decimal digits computed beyond those requested, and terms
+// beyond the convergence bound. main sets them from -guard-digits and
+// -guard-terms before any computation starts; they are read-only after that.
+var (
+	guardDigits = defaultGuardDigits
+	guardTerms  = defaultGuardTerms
+)
+
 // mul returns x·y, using FFT for large operands and Karatsuba otherwise.
 func mul(x, y *big.Int) *big.Int {
 	if x.BitLen() >= fftMinBits && y.BitLen() >= fftMinBits {
@@ -131,17 +161,33 @@ func binarySplit(a, b int64) (P, Q, R *big.Int) {
 // (starting at the root) can skip forming P = P1·P2 — the largest discarded
 // multiply in the whole computation.
 func parallelSplit(a, b int64, needP bool) (P, Q, R *big.Int) {
+	return splitProfiled(a, b, needP, 0, nil)
+}
+
+// splitProfiled is parallelSplit for the node at merge level depth, recording
+// its leaves and combines into prof when prof is non-nil. With a nil prof it
+// does exactly parallelSplit's work: no clock reads.
+func splitProfiled(a, b int64, needP bool, depth int, prof *splitProfile) (P, Q, R *big.Int) {
 	if b-a < serialCutoff {
+		if prof == nil {
+			return binarySplit(a, b)
+		}
+		t := time.Now()
 		P, Q, R = binarySplit(a, b)
+		prof.leaf(time.Since(t))
 		return
 	}
 	m := (a + b) / 2
 	var P1, Q1, R1, P2, Q2, R2 *big.Int
 	var wg sync.WaitGroup
 	wg.Add(2)
-	go func() { defer wg.Done(); P1, Q1, R1 = parallelSplit(a, m, true) }()
-	go func() { defer wg.Done(); P2, Q2, R2 = parallelSplit(m, b, needP) }()
+	go func() { defer wg.Done(); P1, Q1, R1 = splitProfiled(a, m, true, depth+1, prof) }()
+	go func() { defer wg.Done(); P2, Q2, R2 = splitProfiled(m, b, needP, depth+1, prof) }()
 	wg.Wait()
+	var t time.Time
+	if prof != nil {
+		t = time.Now()
+	}
 
 	// Combine. Q, R1·Q2 and P1·R2 are independent products; run them
 	// concurrently when the operands are large enough to be worth a goroutine.
@@ -169,18 +215,93 @@ func parallelSplit(a, b int64, needP bool) (P, Q, R *big.Int) {
 	Q = qq
 	R = new(big.Int).Add(rq, pr)
 	P = pp // nil when needP is false
+	if prof != nil {
+		prof.merge(depth, Q1.BitLen(), time.Since(t))
+	}
 	return
 }
 
 // terms returns the number of Chudnovsky terms needed for d correct decimals.
 func terms(d int) int64 {
-	return int64(math.Ceil(float64(d)/digitsPerTerm)) + 4
+	return int64(math.Ceil(float64(d)/digitsPerTerm)) + int64(guardTerms)
+}
+
+// guardTermsUsed returns how many guard terms the series for d digits
+// actually needs: the count beyond ⌈d/digitsPerTerm⌉ at which the first
+// omitted term, relative to the k = 0 term, drops below 10^-d.
+func guardTermsUsed(d int) int {
+	base := int64(math.Ceil(float64(d) / digitsPerTerm))
+	n := base
+	for log10Term(n) >= -float64(d) {
+		n++
+	}
+	return int(n - base)
+}
+
+// log10Term returns log10 of |a_k / a_0| for the Chudnovsky term
+// a_k = (−1)^k (6k)! (13591409 + 545140134k) / ((3k)! (k!)³ 640320^(3k)).
+func log10Term(k int64) float64 {
+	lg := func(x float64) float64 { v, _ := math.Lgamma(x); return v }
+	x := float64(k)
+	l := lg(6*x+1) - lg(3*x+1) - 3*lg(x+1) - 3*x*math.Log(640320) +
+		math.Log(13591409+545140134*x) - math.Log(13591409)
+	return l / math.Ln10
+}
+
+// guardRun returns how many of the guard digits g (the remainder below the cut,
+// guard digits wide) a carry would have to cross: the length of the run of 9s
+// or 0s they start with. While it stays below guard−1 the few-ulp pipeline
+// error cannot reach the requested digits.
+func guardRun(g *big.Int, guard int) int {
+	if guard == 0 {
+		return 0
+	}
+	s := fmt.Sprintf("%0*s", guard, g.String())
+	if s[0] != '9' && s[0] != '0' {
+		return 0
+	}
+	n := 1
+	for n < len(s) && s[n] == s[0] {
+		n++
+	}
+	return n
 }
 
 // stageTimes records per-stage durations when piFloor is asked to profile.
 // sqrt runs concurrently with split; sqrtTail is the part of its wall time not
-// hidden behind the split (zero when the split finishes last).
-type stageTimes struct{ split, sqrt, sqrtTail, div time.Duration }
+// hidden behind the split (zero when the split finishes last). levels breaks
+// the split down by merge level, and is filled only when profile is set: the
+// per-node clock reads stay off runs that will not print it. guard and
+// gTerms are the digit and term guards the run used; guardUsed and termsUsed
+// report how much of each it consumed (see guardRun, guardTermsUsed), and
+// hexGuardUsed is the digit guard consumed at the hex cut, when hex is set.
+type stageTimes struct {
+	split, sqrt, sqrtTail, div time.Duration
+	profile                    bool
+	levels                     splitProfile
+	guard, guardUsed           int
+	hex                        bool
+	hexGuardUsed               int
+	gTerms, termsUsed          int
+}
+
+// print writes the stage timings, the guard consumed and the split's
+// per-level histogram.
+func (st *stageTimes) print(w io.Writer) {
+	fmt.Fprintf(w, "  split %v, sqrt %v (%v exposed), div %v\n", st.split, st.sqrt, st.sqrtTail, st.div)
+	fmt.Fprintf(w, "  guard: %d of %d digits consumed", st.guardUsed, st.guard)
+	if st.hex {
+		fmt.Fprintf(w, " (%d at the hex cut)", st.hexGuardUsed)
+	}
+	fmt.Fprintf(w, ", %d of %d terms needed\n", st.termsUsed, st.gTerms)
+	st.levels.write(w)
+}
+
+// guardExhausted reports whether a 9s/0s run below either cut reached the last
+// guard digit, where the pipeline's few-ulp error could carry into the result.
+func (st *stageTimes) guardExhausted() bool {
+	return max(st.guardUsed, st.hexGuardUsed) >= st.guard-1
+}
 
 // piFloor returns ⌊π·10^d⌋ as a big.Int — its decimal string is "3" followed by
 // the first d decimal digits of π. The whole pipeline is integer arithmetic
@@ -191,7 +312,48 @@ func piFloor(d int, st *stageTimes) *big.Int { return piFloorGuard(d, guardDigit
 // piFloorGuard is piFloor with an explicit guard, so tests can confirm the
 // result is stable as the guard grows (a too-small guard would diverge).
 func piFloorGuard(d, guard int, st *stageTimes) *big.Int {
-	total := d + guard
+	v := piScaled(d+guard, st)
+	g := new(big.Int)
+	v.QuoRem(v, pow10(guard), g) // drop the guard digits → ⌊π·10^d⌋
+	st.recordGuard(g, guard)
+	return v
+}
+
+// recordGuard notes the guard digits g left below the cut. A nil st is a no-op.
+func (st *stageTimes) recordGuard(g *big.Int, guard int) {
+	if st != nil {
+		st.guard, st.guardUsed = guard, guardRun(g, guard)
+	}
+}
+
+// piFloorDual returns ⌊π·10^d⌋ and ⌊π·16^h⌋ for h = hexPlaces(d), both cut
+// from a single computation of the scaled value. 16^h ≤ 10^d, so the hex
+// floor sees the pipeline's few-ulp error scaled down by at least 10^guard,
+// exactly as the decimal one does. Dividing v·16^h by 10^d rather than by
+// 10^total keeps guard decimal digits of the hex fraction below the cut, and
+// the same run test as the decimal cut vouches for the last hex place.
+func piFloorDual(d int, st *stageTimes) (dec, hex *big.Int) {
+	total := d + guardDigits
+	v := piScaled(total, st)
+	hg := new(big.Int)
+	hex = divFFT(new(big.Int).Lsh(v, uint(4*hexPlaces(d))), pow10(d)) // ⌊π·16^h·10^guard⌋
+	hex.QuoRem(hex, pow10(guardDigits), hg)
+	g := new(big.Int)
+	dec, _ = v.QuoRem(v, pow10(guardDigits), g)
+	st.recordGuard(g, guardDigits)
+	if st != nil {
+		st.hex, st.hexGuardUsed = true, guardRun(hg, guardDigits)
+	}
+	return
+}
+
+// hexPlaces returns the number of hexadecimal places carried by d decimal
+// places of precision: the largest h with 16^h ≤ 10^d.
+func hexPlaces(d int) int { return int(float64(d) * log16of10) }
+
+// piScaled returns ⌊π·10^total⌋, possibly a few ulps off — callers drop guard
+// digits to absorb that.
+func piScaled(total int, st *stageTimes) *big.Int {
 
 	// S = ⌊√10005 · 10^total⌋ (FFT inverse-square-root) depends on nothing from
 	// the split, so it runs concurrently: the split saturates every core while
@@ -212,10 +374,15 @@ func piFloorGuard(d, guard int, st *stageTimes) *big.Int {
 	}()
 
 	t := time.Now()
-	_, Q, R := parallelSplit(1, terms(total), false)
+	var prof *splitProfile
+	if st != nil && st.profile {
+		prof = &st.levels
+	}
+	_, Q, R := splitProfiled(1, terms(total), false, 0, prof)
 	splitDone := time.Now()
 	swg.Wait()
 	if st != nil {
+		st.gTerms, st.termsUsed = guardTerms, guardTermsUsed(total)
 		st.split = splitDone.Sub(t)
 		st.sqrt = sqrtDur
 		if st.sqrtTail = sqrtDone.Sub(splitDone); st.sqrtTail < 0 {
@@ -244,8 +411,7 @@ func piFloorGuard(d, guard int, st *stageTimes) *big.Int {
 	if st != nil {
 		st.div = time.Since(t)
 	}
-
-	return v.Quo(v, pow10(guard)) // drop the guard digits → ⌊π·10^d⌋
+	return v
 }
 
 // extractWindow returns the digit at digitPos (1-based; position 1 is the
@@ -274,32 +440,132 @@ func piDecimalString(d int) string {
 	return piFloor(d, nil).String()
 }
 
+// formatContext renders extractWindow's result as "...before[digit]after...",
+// trimming the positions before the integer part for small digitPos.
+func formatContext(digitPos, digit int, window string) string {
+	before := window[:ctxWindow]
+	after := window[ctxWindow+1:]
+	if digitPos <= ctxWindow {
+		before = before[ctxWindow-(digitPos-1):]
+	}
+	return fmt.Sprintf("...%s[%d]%s...", before, digit, after)
+}
+
+// warnGuard tells stderr when a run consumed its whole digit guard at either
+// cut, so a carry from the pipeline's few-ulp error could have reached the
+// result.
+func warnGuard(st *stageTimes) {
+	if st.guardExhausted() {
+		fmt.Fprintf(os.Stderr, "warning: %d of %d guard digits are a run of 9s/0s; the last digits may be wrong — rerun with a larger -guard-digits\n",
+			max(st.guardUsed, st.hexGuardUsed), st.guard)
+	}
+}
+
+// writeExpansion writes s and a trailing newline to path, exiting on failure.
+func writeExpansion(path, s string) {
+	if err := os.WriteFile(path, []byte(s+"\n"), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitIO)
+	}
+}
+
 func main() {
 	digitPos := flag.Int("digit", 10000, "which digit of π to compute (digit 1 = the integer part '3')")
 	all := flag.Bool("all", false, "print π to `-digit` places instead of just the digit at that position")
-	verbose := flag.Bool("verbose", false, "print stage timings")
+	verbose := flag.Bool("verbose", false, "print stage timings and the split's per-merge-level histogram")
+	out := flag.String("out", "", "with -all, write the decimal expansion to this file instead of stdout")
+	hexOut := flag.String("hex-out", "", "with -all, also write the hexadecimal expansion from the same computation to this file")
+	format := flag.String("format", "plain", "with -all, layout preset for the expansion: "+strings.Join(presetNames(), ", "))
+	selftest := flag.Bool("selftest", false, "run the end-to-end self-test (golden digests up to 10^6 digits) and exit")
+	quick := flag.Bool("quick", false, "compute a 10^3–10^5 digit ladder, check each against its golden digest, print timings and exit")
+	compare := flag.String("compare", "", "developer mode: compute π to -digit places with the two backends `a,b` ("+strings.Join(backendNames(), ", ")+") concurrently and report the first divergence")
+	gDigits := flag.Int("guard-digits", defaultGuardDigits, fmt.Sprintf("decimal guard digits computed beyond those requested (min %d)", minGuardDigits))
+	gTerms := flag.Int("guard-terms", defaultGuardTerms, fmt.Sprintf("series terms computed beyond the convergence bound (min %d); pure margin — the series needs none of them up to 10^9 digits", minGuardTerms))
 	flag.Parse()
+	if *gDigits < minGuardDigits || *gTerms < minGuardTerms {
+		fmt.Fprintf(os.Stderr, "-guard-digits must be at least %d and -guard-terms at least %d\n", minGuardDigits, minGuardTerms)
+		os.Exit(exitUsage)
+	}
+	guardDigits, guardTerms = *gDigits, *gTerms
+	if *selftest {
+		setInvSqrtCache(true)
+		fmt.Printf("Using %d CPU cores\n", runtime.NumCPU())
+		if !runSelfTest(os.Stdout, goldenDigests[len(goldenDigests)-1].digits) {
+			fmt.Println("Self-test FAILED")
+			os.Exit(exitMismatch)
+		}
+		fmt.Println("Self-test passed")
+		return
+	}
+	if *quick {
+		setInvSqrtCache(true)
+		fmt.Printf("Using %d CPU cores\n", runtime.NumCPU())
+		t := time.Now()
+		if !runQuick(os.Stdout) {
+			fmt.Println("Quick check FAILED")
+			os.Exit(exitMismatch)
+		}
+		fmt.Printf("Quick check passed in %v\n", time.Since(t).Round(time.Millisecond))
+		return
+	}
 	if *digitPos < 1 {
 		*digitPos = 1
 	}
+	if !*all && (*out != "" || *hexOut != "" || *format != "plain") {
+		fmt.Fprintln(os.Stderr, "-out, -hex-out and -format require -all")
+		os.Exit(exitUsage)
+	}
+	if *compare != "" {
+		x, y, ok := parseCompare(*compare)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "-compare wants two of: %s (got %q)\n", strings.Join(backendNames(), ", "), *compare)
+			os.Exit(exitUsage)
+		}
+		fmt.Printf("Using %d CPU cores\n", runtime.NumCPU())
+		fmt.Printf("Comparing %s against %s to %d places\n\n", x.name, y.name, *digitPos)
+		if !runCompare(os.Stdout, x, y, *digitPos-1) {
+			os.Exit(exitMismatch)
+		}
+		return
+	}
+	layout, ok := formatPresets[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown -format %q (want one of: %s)\n", *format, strings.Join(presetNames(), ", "))
+		os.Exit(exitUsage)
+	}
 
 	fmt.Printf("Using %d CPU cores\n", runtime.NumCPU())
 
-	var st stageTimes
+	st := stageTimes{profile: *verbose}
 	start := time.Now()
 
 	if *all {
 		// Full expansion: positions 1..digitPos ("3" + digitPos-1 decimals).
 		fmt.Printf("Computing π to %d places\n\n", *digitPos)
-		s := piFloor(*digitPos-1, &st).String()
+		var s, hs string
+		if *hexOut != "" {
+			dec, hex := piFloorDual(*digitPos-1, &st)
+			s, hs = layout.render(dec.String()), layout.render(hex.Text(16))
+		} else {
+			s = layout.render(piFloor(*digitPos-1, &st).String())
+		}
 		elapsed := time.Since(start)
-		if len(s) > 1 {
-			s = s[:1] + "." + s[1:]
+		warnGuard(&st)
+		if *out != "" {
+			writeExpansion(*out, s)
+			fmt.Printf("Decimal expansion written to %s\n", *out)
+		} else if layout.line > 0 {
+			fmt.Printf("π =\n%s\n", s)
+		} else {
+			fmt.Printf("π = %s\n", s)
+		}
+		if *hexOut != "" {
+			writeExpansion(*hexOut, hs)
+			fmt.Printf("Hexadecimal expansion (%d places) written to %s\n", hexPlaces(*digitPos-1), *hexOut)
 		}
-		fmt.Printf("π = %s\n", s)
 		fmt.Printf("Total time: %v\n", elapsed)
 		if *verbose {
-			fmt.Printf("  split %v, sqrt %v (%v exposed), div %v\n", st.split, st.sqrt, st.sqrtTail, st.div)
+			st.print(os.Stdout)
 		}
 		return
 	}
@@ -307,18 +573,13 @@ func main() {
 	fmt.Printf("Calculating digit %d of π\n\n", *digitPos)
 	digit, window := extractWindow(*digitPos, &st)
 	elapsed := time.Since(start)
+	warnGuard(&st)
 
 	fmt.Printf("Digit %d of π is: %d\n", *digitPos, digit)
 	fmt.Printf("Total time: %v\n", elapsed)
 	if *verbose {
-		fmt.Printf("  split %v, sqrt %v (%v exposed), div %v\n", st.split, st.sqrt, st.sqrtTail, st.div)
+		st.print(os.Stdout)
 	}
 
-	// Context window: trim positions before the integer part for small digitPos.
-	before := window[:ctxWindow]
-	after := window[ctxWindow+1:]
-	if *digitPos <= ctxWindow {
-		before = before[ctxWindow-(*digitPos-1):]
-	}
-	fmt.Printf("Context: ...%s[%d]%s...\n", before, digit, after)
+	fmt.Printf("Context: %s\n", formatContext(*digitPos, digit, window))
 }
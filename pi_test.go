@@ -144,16 +144,108 @@ func TestGuardBoundary(t *testing.T) {
 	}
 }
 
-// TestGuardStability is a reference-free guard against guardDigits being cut too
-// small: ⌊π·10^d⌋ must be identical no matter how many guard digits we add. The
-// digit at decimal place 4037 rots for guard < ~18, so this fails loudly if the
-// guard regresses. (Position 4038 in the 1-based scheme = decimal place 4037.)
+// TestGuardStability is a reference-free guard against the digit guard being
+// cut too small: ⌊π·10^d⌋ must be identical no matter how many guard digits we
+// add. Both the default and minGuardDigits must hold — the pipeline's error is
+// a few ulps, so any regression that widens it shows up here first.
 func TestGuardStability(t *testing.T) {
 	for _, d := range []int{4038, 8000, 20000} {
-		got := piFloorGuard(d, guardDigits, nil)
 		ref := piFloorGuard(d, guardDigits+64, nil)
-		if got.Cmp(ref) != 0 {
-			t.Fatalf("guardDigits=%d insufficient at d=%d (differs from larger guard)", guardDigits, d)
+		for _, g := range []int{guardDigits, minGuardDigits} {
+			if piFloorGuard(d, g, nil).Cmp(ref) != 0 {
+				t.Fatalf("guard %d insufficient at d=%d (differs from larger guard)", g, d)
+			}
+		}
+	}
+}
+
+// TestPiFloorDual checks the hexadecimal expansion against ⌊piRef·16^h⌋ taken
+// straight from the 1000-decimal reference (exact for h ≤ 800, where 16^h is
+// far below 10^1000), and that the decimal half matches piFloor.
+func TestPiFloorDual(t *testing.T) {
+	ref, _ := new(big.Int).SetString("3"+piRef[2:1002], 10)
+	for _, d := range []int{0, 1, 50, 100, 500, 960} {
+		dec, hex := piFloorDual(d, nil)
+		if want := piFloor(d, nil); dec.Cmp(want) != 0 {
+			t.Fatalf("piFloorDual(%d) decimal part differs from piFloor", d)
+		}
+		h := hexPlaces(d)
+		want := new(big.Int).Lsh(ref, uint(4*h))
+		want.Quo(want, pow10(1000))
+		if hex.Cmp(want) != 0 {
+			t.Fatalf("piFloorDual(%d) hex part wrong:\n got=%s\nwant=%s", d, hex.Text(16), want.Text(16))
+		}
+	}
+	if _, hex := piFloorDual(40, nil); hex.Text(16) != "3243f6a8885a308d313198a2e03707344a" {
+		t.Fatalf("hex prefix = %s", hex.Text(16))
+	}
+}
+
+func TestGuardRun(t *testing.T) {
+	cases := []struct {
+		g     int64
+		guard int
+		want  int
+	}{
+		{0, 0, 0}, {12345678, 8, 0}, {99912345, 8, 3}, {99999999, 8, 8},
+		{123, 8, 5}, // 00000123
+		{0, 8, 8},
+	}
+	for _, c := range cases {
+		if got := guardRun(big.NewInt(c.g), c.guard); got != c.want {
+			t.Errorf("guardRun(%d, %d) = %d, want %d", c.g, c.guard, got, c.want)
+		}
+	}
+	// Cutting just before the Feynman point leaves its six 9s at the top of
+	// the guard.
+	var st stageTimes
+	piFloorGuard(761, minGuardDigits, &st)
+	if st.guardUsed != 6 || st.guardExhausted() {
+		t.Fatalf("guard at the Feynman point: %d of %d used (exhausted=%v), want 6, not exhausted",
+			st.guardUsed, st.guard, st.guardExhausted())
+	}
+}
+
+// TestMinimumGuards checks the policy floors: the series needs none of the
+// default guard terms at any size up to 10⁹ digits, and the minimum digit and
+// term guards still reproduce the reference.
+func TestMinimumGuards(t *testing.T) {
+	for _, d := range []int{1, 100, 1032, 10032, 1000032, 100000032, 1000000000} {
+		if used := guardTermsUsed(d); used > defaultGuardTerms {
+			t.Errorf("guardTermsUsed(%d) = %d, exceeds the default %d", d, used, defaultGuardTerms)
+		}
+	}
+	defer func(g, tm int) { guardDigits, guardTerms = g, tm }(guardDigits, guardTerms)
+	guardDigits, guardTerms = minGuardDigits, minGuardTerms
+	var st stageTimes
+	piFloor(100, &st)
+	if st.guard != minGuardDigits || st.gTerms != minGuardTerms {
+		t.Fatalf("stageTimes recorded guards %d/%d, want %d/%d", st.guard, st.gTerms, minGuardDigits, minGuardTerms)
+	}
+	for _, d := range []int{0, 1, 100, 1000} {
+		if got, want := piDecimalString(d), "3"+piRef[2:2+d]; got != want {
+			t.Fatalf("minimum guards: piDecimalString(%d) wrong", d)
 		}
 	}
 }
+
+// TestHexGuardRun checks the run recorded at the hex cut against the guard
+// digits of the hex fraction taken straight from the 1000-decimal reference.
+func TestHexGuardRun(t *testing.T) {
+	ref, _ := new(big.Int).SetString("3"+piRef[2:1002], 10)
+	seen := false
+	for d := 1; d <= 300; d++ {
+		var st stageTimes
+		piFloorDual(d, &st)
+		f := new(big.Int).Lsh(ref, uint(4*hexPlaces(d)))
+		f.Mod(f, pow10(1000)) // the hex fraction, as 1000 decimals
+		f.Quo(f, pow10(1000-guardDigits))
+		if want := guardRun(f, guardDigits); !st.hex || st.hexGuardUsed != want {
+			t.Fatalf("d=%d: hex guard run %d (hex=%v), want %d", d, st.hexGuardUsed, st.hex, want)
+		}
+		seen = seen || st.hexGuardUsed > 0
+	}
+	if !seen {
+		t.Fatal("no d in range put a 9 or 0 at the top of the hex guard")
+	}
+}
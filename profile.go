@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxSplitDepth bounds the merge levels splitProfile tracks: the split halves
+// down to serialCutoff, so even 2^63 terms stay under 53 levels.
+const maxSplitDepth = 64
+
+// splitProfile accumulates parallelSplit's work per merge level (0 = the
+// root combine): the number of combines, their summed wall time, and the
+// summed bit length of their Q1 operand. The serial subtrees below the cutoff
+// are tallied separately as leaves. Durations are summed across concurrent
+// nodes, so when more nodes are runnable than there are cores a level's time
+// also counts waiting for a core — read the shares, not the absolute times.
+type splitProfile struct {
+	levels           [maxSplitDepth]struct{ n, nanos, qbits atomic.Int64 }
+	leafN, leafNanos atomic.Int64
+	maxDepth         atomic.Int64
+}
+
+// merge records one combine at the given level.
+func (p *splitProfile) merge(depth, qbits int, d time.Duration) {
+	l := &p.levels[depth]
+	l.n.Add(1)
+	l.nanos.Add(int64(d))
+	l.qbits.Add(int64(qbits))
+	for {
+		m := p.maxDepth.Load()
+		if int64(depth) <= m || p.maxDepth.CompareAndSwap(m, int64(depth)) {
+			return
+		}
+	}
+}
+
+// leaf records one serial subtree.
+func (p *splitProfile) leaf(d time.Duration) {
+	p.leafN.Add(1)
+	p.leafNanos.Add(int64(d))
+}
+
+// write prints the per-level histogram to w, one row per merge level and a
+// final row for the leaves, each with its share of the total split work.
+func (p *splitProfile) write(w io.Writer) {
+	total := p.leafNanos.Load()
+	levels := int(p.maxDepth.Load()) + 1
+	if p.levels[0].n.Load() == 0 {
+		levels = 0 // the root was itself a leaf
+	}
+	for i := range levels {
+		total += p.levels[i].nanos.Load()
+	}
+	if total == 0 {
+		return
+	}
+	row := func(name string, n, nanos int64, bits string) {
+		share := float64(nanos) / float64(total)
+		fmt.Fprintf(w, "    %-6s %7d %12s %14v %5.1f%% %s\n", name, n, bits,
+			time.Duration(nanos).Round(time.Microsecond), 100*share, strings.Repeat("#", int(share*40+0.5)))
+	}
+	fmt.Fprintf(w, "    %-6s %7s %12s %14s %6s\n", "level", "nodes", "avg Q bits", "combine time", "share")
+	for i := range levels {
+		l := &p.levels[i]
+		n := l.n.Load()
+		row(fmt.Sprint(i), n, l.nanos.Load(), fmt.Sprint(l.qbits.Load()/n))
+	}
+	row("leaves", p.leafN.Load(), p.leafNanos.Load(), "-")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// goldenDigests pins SHA-256 digests of the decimal string ⌊π·10^digits⌋ and
+// of the hex string ⌊π·16^hexPlaces(digits)⌋ at several sizes. They were
+// generated by an independent implementation (plain Python integers,
+// stdlib-only: no FFT, no truncation, exact integer √), so a match exercises
+// the whole FFT/parallel/truncated pipeline against arithmetic that shares
+// none of it.
+var goldenDigests = []struct {
+	digits   int
+	dec, hex string
+}{
+	{1000, "8a84d58d47f78c42a200ebfbbe7346426d4cdf4f2803d45c3590162865fcc0d2", "68bde5be5f8c512536138a6dc1e826ba9a3dd96cf84367ab6afc1a4381774264"},
+	{10000, "ab03bf7037718e1525340a0c43f0049ddbe99c6067ad3e08a4de5c591fedc524", "d06164ec088a23ea8803ef2fc5dbe2f2b033103c76acc8b30d67fc580cd269ee"},
+	{100000, "79b862cc31ceb97bb3c39d05ae74664c99f0ff7670b3e08a4b21a31ef84d7711", "284b450d24dba0743b86fc3b9a5d79ed6db1c8cbee38224b2a8842b84ab5d057"},
+	{1000000, "130203eb055a962b8441af76c22b75627ec18c672a485904e567f59251e8ee18", "72e50ffad0d1124240e892afc58f7f69c5e5215c5f6879c031a7241afbc28774"},
+}
+
+// selfCheck is one end-to-end check run by -selftest.
+type selfCheck struct {
+	name string
+	run  func() error
+}
+
+// selfChecks returns the -selftest checks for expansions up to maxDigits
+// decimals: serial/parallel agreement, golden digests (decimal and hex, one
+// computation each), digit extraction and the context window, and formatting.
+func selfChecks(maxDigits int) []selfCheck {
+	checks := []selfCheck{
+		{"parallel split matches serial", func() error {
+			// Past the serial cutoff, so the root combines on the mul dispatcher.
+			n := int64(2*serialCutoff + 17)
+			wP, wQ, wR := binarySplit(1, n)
+			gP, gQ, gR := parallelSplit(1, n, true)
+			if !eq(wP, gP) || !eq(wQ, gQ) || !eq(wR, gR) {
+				return fmt.Errorf("parallel != serial for [1,%d)", n)
+			}
+			return nil
+		}},
+		{"formatting", func() error {
+			s := piDecimalString(12)
+			if got := formatPresets["plain"].render(s); got != "3.141592653589" {
+				return fmt.Errorf("plain expansion = %q", got)
+			}
+			if got := formatPresets["classroom"].render(s); got != "3.14159 26535 89" {
+				return fmt.Errorf("classroom expansion = %q", got)
+			}
+			if got := formatContext(3, 4, "00031415926"); got != "...31[4]15926..." {
+				return fmt.Errorf("context = %q", got)
+			}
+			return nil
+		}},
+	}
+	for _, g := range goldenDigests {
+		if g.digits > maxDigits {
+			continue
+		}
+		checks = append(checks, selfCheck{fmt.Sprintf("%d digits: digests and extraction", g.digits), func() error {
+			dec, hx := piFloorDual(g.digits, nil)
+			s := dec.String()
+			if sha256Hex(s) != g.dec {
+				return fmt.Errorf("decimal digest mismatch")
+			}
+			if sha256Hex(hx.Text(16)) != g.hex {
+				return fmt.Errorf("hex digest mismatch")
+			}
+			// The digest vouches for s; the extraction path must agree with its
+			// last window (the deepest position whose context s still covers).
+			pos := len(s) - ctxWindow
+			digit, window := extractWindow(pos, nil)
+			if want := s[pos-1-ctxWindow:]; digit != int(s[pos-1]-'0') || window != want {
+				return fmt.Errorf("extractWindow(%d) = %d %q, want %q", pos, digit, window, want)
+			}
+			return nil
+		}})
+	}
+	return checks
+}
+
+// runSelfTest runs selfChecks(maxDigits), reporting each to w, and reports
+// whether all of them passed.
+func runSelfTest(w io.Writer, maxDigits int) bool {
+	ok := true
+	for _, c := range selfChecks(maxDigits) {
+		t := time.Now()
+		err := c.run()
+		if err != nil {
+			ok = false
+			fmt.Fprintf(w, "FAIL %s: %v\n", c.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "ok   %s (%v)\n", c.name, time.Since(t).Round(time.Microsecond))
+	}
+	return ok
+}
+
+// quickSizes is the -quick ladder: each size has a golden digest, and the
+// whole ladder stays well under a second on a laptop.
+var quickSizes = []int{1000, 10000, 100000}
+
+// runQuick computes each quickSizes expansion, checks it against its golden
+// digest and reports the timing to w; it reports whether all of them matched.
+func runQuick(w io.Writer) bool {
+	ok := true
+	for _, d := range quickSizes {
+		t := time.Now()
+		s := piFloor(d, nil).String()
+		elapsed := time.Since(t)
+		status := "ok  "
+		if sha256Hex(s) != goldenDigest(d) {
+			status, ok = "FAIL", false
+		}
+		fmt.Fprintf(w, "%s %7d digits  %v\n", status, d, elapsed.Round(time.Microsecond))
+	}
+	return ok
+}
+
+// goldenDigest returns the golden decimal digest for d digits, or "" if none
+// is pinned.
+func goldenDigest(d int) string {
+	for _, g := range goldenDigests {
+		if g.digits == d {
+			return g.dec
+		}
+	}
+	return ""
+}
+
+// eq reports whether a and b are equal, treating two nils as equal.
+func eq(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Cmp(b) == 0
+}
+
+// sha256Hex returns the hex-encoded SHA-256 digest of s.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
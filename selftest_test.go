@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGoldenDigestRef ties the smallest golden digest to the in-repo
+// reference, so a bad digest table cannot pass the self-test vacuously.
+func TestGoldenDigestRef(t *testing.T) {
+	g := goldenDigests[0]
+	if got := sha256Hex("3" + piRef[2:2+g.digits]); got != g.dec {
+		t.Fatalf("golden digest for %d digits = %s, reference hashes to %s", g.digits, g.dec, got)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	maxDigits := goldenDigests[len(goldenDigests)-1].digits
+	if testing.Short() {
+		maxDigits = 100000
+	}
+	var out strings.Builder
+	if !runSelfTest(&out, maxDigits) {
+		t.Fatalf("self-test failed:\n%s", out.String())
+	}
+}
+
+func TestQuick(t *testing.T) {
+	for _, d := range quickSizes {
+		if goldenDigest(d) == "" {
+			t.Fatalf("quick size %d has no golden digest", d)
+		}
+	}
+	var out strings.Builder
+	if !runQuick(&out) {
+		t.Fatalf("quick ladder failed:\n%s", out.String())
+	}
+}
@@ -6,13 +6,6 @@ import (
 	"testing"
 )
 
-func eq(a, b *big.Int) bool {
-	if a == nil || b == nil {
-		return a == nil && b == nil
-	}
-	return a.Cmp(b) == 0
-}
-
 // TestParallelMatchesSerial checks that the parallel, FFT-using split produces
 // bit-identical (P,Q,R) to the serial, stdlib-only reference across many
 // ranges — including ranges large enough to exercise the FFT multiply path.
@@ -143,3 +136,43 @@ func TestMulMatchesStdlib(t *testing.T) {
 		}
 	}
 }
+
+// TestSplitProfile checks that profiling leaves the split's result unchanged
+// and counts the tree's shape: 2^k combines at level k and one leaf per
+// serial subtree.
+func TestSplitProfile(t *testing.T) {
+	const n = 1 + 8*(serialCutoff-1) // three merge levels over 8 leaves of cutoff−1 terms
+	var prof splitProfile
+	_, Q, R := splitProfiled(1, n, false, 0, &prof)
+	_, wQ, wR := parallelSplit(1, n, false)
+	if !eq(Q, wQ) || !eq(R, wR) {
+		t.Fatal("profiled split differs from parallelSplit")
+	}
+	if d := prof.maxDepth.Load(); d != 2 {
+		t.Fatalf("max merge depth = %d, want 2", d)
+	}
+	for k := range 3 {
+		if got := prof.levels[k].n.Load(); got != 1<<k {
+			t.Errorf("level %d: %d combines, want %d", k, got, 1<<k)
+		}
+	}
+	if got := prof.leafN.Load(); got != 8 {
+		t.Errorf("%d leaves, want 8", got)
+	}
+}
+
+// TestStageProfileOptIn checks that piFloor fills the per-level profile only
+// when the caller asks for it.
+func TestStageProfileOptIn(t *testing.T) {
+	const d = 100000 // terms well past the serial cutoff, so the root combines
+	var off stageTimes
+	piFloor(d, &off)
+	if off.levels.leafN.Load() != 0 || off.levels.levels[0].n.Load() != 0 {
+		t.Fatal("unprofiled run recorded split levels")
+	}
+	on := stageTimes{profile: true}
+	piFloor(d, &on)
+	if on.levels.levels[0].n.Load() != 1 || on.levels.leafN.Load() == 0 {
+		t.Fatal("profiled run did not record split levels")
+	}
+}
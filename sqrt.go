@@ -3,6 +3,7 @@ package main
 import (
 	"math"
 	"math/big"
+	"sync"
 )
 
 // invSqrtBase is the precision (in bits) at which the Newton recursion bottoms
@@ -21,12 +22,63 @@ const invSqrtBase = 1 << 16
 // guard digits absorb that.
 func sqrt10005Scaled(total int) *big.Int {
 	p := uint(math.Ceil(float64(total)*log2of10)) + 64 // 2^p ≫ 10005·10^total
-	r := invSqrtConst(10005, p)                        // ≈ ⌊2^p / √10005⌋
+	r := invSqrt10005(p)                               // ≈ ⌊2^p / √10005⌋
 	s := new(big.Int).Mul(big.NewInt(10005), r)        // ≈ √10005 · 2^p
 	s = mulPar(s, pow5(total))                         // · 5^total  (FFT)
 	return s.Rsh(s, p-uint(total))                     // ⌊√10005 · 10^total⌋ — the ·2^total folds into the shift
 }
 
+// invSqrtCache holds the highest-precision ⌊2^p/√10005⌋ computed so far. Any
+// lower precision is a truncation of it, and a higher one only pays for the
+// Newton levels above p — the recursion bottoms out in the cached value
+// instead of in the stdlib √. Entries are never mutated once stored.
+//
+// The cache is off unless a multi-run mode (-selftest, -quick) turns it on: a
+// one-shot run never reads it back, and holding the ~3.3·total bit entry
+// through the division would only raise peak memory.
+var invSqrtCache struct {
+	sync.Mutex
+	on bool
+	p  uint
+	r  *big.Int
+}
+
+// setInvSqrtCache turns invSqrtCache on or off; turning it off drops the
+// stored entry.
+func setInvSqrtCache(on bool) {
+	invSqrtCache.Lock()
+	invSqrtCache.on = on
+	if !on {
+		invSqrtCache.p, invSqrtCache.r = 0, nil
+	}
+	invSqrtCache.Unlock()
+}
+
+// invSqrt10005 returns ≈ ⌊2^p / √10005⌋, through invSqrtCache when it is on
+// and straight from invSqrtConst otherwise. Truncating the cached value by j
+// bits floors it again, so the result stays floor-biased and no further below
+// the exact floor than a fresh invSqrtConst. The lock is not held while Newton
+// runs; concurrent callers may both extend the cache, and the more precise
+// result is kept.
+func invSqrt10005(p uint) *big.Int {
+	invSqrtCache.Lock()
+	on, pc, rc := invSqrtCache.on, invSqrtCache.p, invSqrtCache.r
+	invSqrtCache.Unlock()
+	if !on {
+		return invSqrtConst(10005, p)
+	}
+	if rc != nil && p <= pc {
+		return new(big.Int).Rsh(rc, pc-p)
+	}
+	r := invSqrtSeeded(10005, p, rc, pc)
+	invSqrtCache.Lock()
+	if invSqrtCache.on && p > invSqrtCache.p {
+		invSqrtCache.p, invSqrtCache.r = p, r
+	}
+	invSqrtCache.Unlock()
+	return r
+}
+
 // invSqrtConst returns ≈ ⌊2^p / √c⌋ for a small positive constant c, via Newton
 // with precision doubling. The iteration y ← y·(3·2^(2p) − c·y²) >> (2p+1)
 // converges quadratically. With t = 3·2^(2p) − c·y² written as 2^(2p+1) + δ,
@@ -35,16 +87,23 @@ func sqrt10005Scaled(total int) *big.Int {
 // on the unshifted half-precision ρ rather than on y = ρ·2^(p−pp), whose
 // trailing zeros the FFT would otherwise pay for. Per-level multiply volume
 // drops from ~5p to ~3p output bits.
-func invSqrtConst(c int64, p uint) *big.Int {
+func invSqrtConst(c int64, p uint) *big.Int { return invSqrtSeeded(c, p, nil, 0) }
+
+// invSqrtSeeded is invSqrtConst with a known ≈ ⌊2^ps/√c⌋ seed: levels at or
+// below ps are truncations of it rather than further recursion.
+func invSqrtSeeded(c int64, p uint, seed *big.Int, ps uint) *big.Int {
+	if seed != nil && p <= ps {
+		return new(big.Int).Rsh(seed, ps-p)
+	}
 	if p <= invSqrtBase {
 		a := new(big.Int).Lsh(bigOne, 2*p)
 		a.Quo(a, big.NewInt(c))
 		return a.Sqrt(a)
 	}
 	pp := p/2 + 16
-	rho := invSqrtConst(c, pp)       // ≈ 2^pp/√c, kept unshifted
-	y2 := mulPar(rho, rho)           // ρ² (FFT squaring)
-	cy2 := y2.Mul(y2, big.NewInt(c)) // scalar multiply
+	rho := invSqrtSeeded(c, pp, seed, ps) // ≈ 2^pp/√c, kept unshifted
+	y2 := mulPar(rho, rho)                // ρ² (FFT squaring)
+	cy2 := y2.Mul(y2, big.NewInt(c))      // scalar multiply
 	delta := new(big.Int).Lsh(bigOne, 2*p)
 	delta.Sub(delta, cy2.Lsh(cy2, 2*(p-pp))) // δ = 2^(2p) − c·y²  (y = ρ·2^(p−pp))
 	yd := mulPar(rho, delta)                 // ρ·δ
@@ -24,3 +24,37 @@ func TestSqrt10005Scaled(t *testing.T) {
 		}
 	}
 }
+
+// TestInvSqrtCache checks results served from the √10005 cache — truncated
+// from a higher precision and extended by Newton from a lower one — against
+// the exact floor ⌊2^p/√10005⌋ = ⌊√⌊2^(2p)/10005⌋⌋, and that the cache keeps
+// its most precise entry. Off (the default), nothing is stored.
+func TestInvSqrtCache(t *testing.T) {
+	setInvSqrtCache(true)
+	defer setInvSqrtCache(false)
+
+	ps := []uint{1 << 20, 5000, 1<<16 + 1, 300000, 3 << 20}
+	if testing.Short() {
+		ps = ps[:4]
+	}
+	var top uint
+	for _, p := range ps {
+		got := invSqrt10005(p)
+		exact := new(big.Int).Lsh(bigOne, 2*p)
+		exact.Sqrt(exact.Quo(exact, big.NewInt(10005)))
+		diff := new(big.Int).Sub(exact, got)
+		if diff.Sign() < 0 || diff.Cmp(big.NewInt(1)) > 0 {
+			t.Fatalf("invSqrt10005(%d) off by %s (must be 0 or 1 below exact)", p, diff)
+		}
+		top = max(top, p)
+		if invSqrtCache.p != top {
+			t.Fatalf("after p=%d cache holds p=%d, want %d", p, invSqrtCache.p, top)
+		}
+	}
+
+	setInvSqrtCache(false)
+	invSqrt10005(5000)
+	if invSqrtCache.r != nil {
+		t.Fatal("disabled cache stored an entry")
+	}
+}